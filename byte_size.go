@@ -0,0 +1,44 @@
+package httpd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a pflag.Value that accepts plain byte counts or human readable
+// sizes with a KB/MB/GB suffix (e.g. "1MB", "512KB").
+type ByteSize int64
+
+func (b *ByteSize) String() string {
+	if b == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *ByteSize) Set(value string) error {
+	v := strings.TrimSpace(value)
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(v, "GB"):
+		mult = 1 << 30
+		v = strings.TrimSuffix(v, "GB")
+	case strings.HasSuffix(v, "MB"):
+		mult = 1 << 20
+		v = strings.TrimSuffix(v, "MB")
+	case strings.HasSuffix(v, "KB"):
+		mult = 1 << 10
+		v = strings.TrimSuffix(v, "KB")
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid byte size %q: %w", value, err)
+	}
+	*b = ByteSize(n * mult)
+	return nil
+}
+
+func (b *ByteSize) Type() string {
+	return "byte-size"
+}
@@ -0,0 +1,434 @@
+package httpd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/net/netutil"
+)
+
+// clientCertContextKey is the context key used to stash the verified client
+// certificate presented during a mutual TLS handshake.
+type clientCertContextKey struct{}
+
+// ClientCertificate returns the verified client certificate presented on the
+// connection that produced r, if the TLS listener was configured for mutual
+// TLS and the client presented one.
+func ClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	cert, ok := r.Context().Value(clientCertContextKey{}).(*x509.Certificate)
+	return cert, ok
+}
+
+// withClientCertificate injects the verified peer certificate, if any, into
+// the request context so downstream handlers can recover the client's
+// subject/SAN without reaching into r.TLS themselves.
+func withClientCertificate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.PeerCertificates) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), clientCertContextKey{}, r.TLS.PeerCertificates[0]))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// clientAuthValue adapts tls.ClientAuthType to pflag.Value so the policy can
+// be selected on the command line by name.
+type clientAuthValue tls.ClientAuthType
+
+var clientAuthPolicies = map[string]tls.ClientAuthType{
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+func (c *clientAuthValue) String() string {
+	if c == nil {
+		return tls.NoClientCert.String()
+	}
+	return tls.ClientAuthType(*c).String()
+}
+
+func (c *clientAuthValue) Set(value string) error {
+	policy, ok := clientAuthPolicies[value]
+	if !ok {
+		return fmt.Errorf("unknown tls client auth policy %q", value)
+	}
+	*c = clientAuthValue(policy)
+	return nil
+}
+
+func (c *clientAuthValue) Type() string {
+	return "client-auth"
+}
+
+// HTTPFlags holds the configuration for the plain HTTP listener.
+type HTTPFlags struct {
+	Host string
+	Port int
+	// ListenLimit bounds the number of simultaneous in-flight connections
+	// accepted on this listener. Zero or negative disables the bound.
+	ListenLimit int
+	// Timeouts holds the Read/Write/Idle/KeepAlive durations applied to
+	// this listener's http.Server and TCP connections.
+	Timeouts Timeouts
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// RegisterFlags to the specified pflag set
+func (h *HTTPFlags) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&h.Host, "host", h.Host, "the IP to listen on")
+	fs.IntVar(&h.Port, "port", h.Port, "the port to listen on for insecure connections, defaults to a random value")
+	fs.IntVar(&h.ListenLimit, "listen-limit", h.ListenLimit, "limit the number of outstanding requests")
+	fs.DurationVar(&h.Timeouts.Read, "read-timeout", 30*time.Second, "maximum duration before timing out read of the request")
+	fs.DurationVar(&h.Timeouts.Write, "write-timeout", 60*time.Second, "maximum duration before timing out write of the response")
+	fs.DurationVar(&h.Timeouts.Idle, "idle-timeout", 60*time.Second, "maximum amount of time to wait for the next request when keep-alives are enabled")
+	fs.DurationVar(&h.Timeouts.KeepAlive, "keep-alive", 3*time.Minute, "TCP keep-alive period for accepted connections, set to 0 to disable")
+}
+
+// Scheme for this listener
+func (h *HTTPFlags) Scheme() string { return schemeHTTP }
+
+// Listener returns the net.Listener for this flag set, creating it if needed
+func (h *HTTPFlags) Listener() (net.Listener, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.listener != nil {
+		return h.listener, nil
+	}
+	lc := net.ListenConfig{KeepAlive: keepAlivePeriod(h.Timeouts.KeepAlive)}
+	l, err := lc.Listen(context.Background(), "tcp", net.JoinHostPort(h.Host, strconv.Itoa(h.Port)))
+	if err != nil {
+		return nil, err
+	}
+	if h.ListenLimit > 0 {
+		l = netutil.LimitListener(l, h.ListenLimit)
+	}
+	h.listener = l
+	return l, nil
+}
+
+// Serve the configured handler on this listener
+func (h *HTTPFlags) Serve(cfg ServerConfig, wg *sync.WaitGroup) (*http.Server, error) {
+	l, err := h.Listener()
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{
+		Handler:        cfg.Handler,
+		MaxHeaderBytes: cfg.MaxHeaderSize,
+		ReadTimeout:    cfg.Timeouts.Read,
+		WriteTimeout:   cfg.Timeouts.Write,
+		IdleTimeout:    cfg.Timeouts.Idle,
+	}
+	if cfg.Callbacks != nil {
+		cfg.Callbacks.ConfigureListener(srv, h.Scheme(), l.Addr().String())
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			cfg.Logger.Error(err, "http listener closed")
+		}
+	}()
+	return srv, nil
+}
+
+// UnixSocketFlags holds the configuration for the unix domain socket listener.
+type UnixSocketFlags struct {
+	Path string
+	Mode string
+	// ListenLimit bounds the number of simultaneous in-flight connections
+	// accepted on this listener. Zero or negative disables the bound.
+	ListenLimit int
+	// Timeouts holds the Read/Write/Idle durations applied to this
+	// listener's http.Server. KeepAlive is not meaningful for unix sockets.
+	Timeouts Timeouts
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// RegisterFlags to the specified pflag set
+func (u *UnixSocketFlags) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&u.Path, "socket-path", u.Path, "the unix socket to listen on")
+	fs.StringVar(&u.Mode, "socket-mode", "0600", "the file mode for the unix socket")
+	fs.IntVar(&u.ListenLimit, "socket-listen-limit", u.ListenLimit, "limit the number of outstanding requests")
+	fs.DurationVar(&u.Timeouts.Read, "socket-read-timeout", 30*time.Second, "maximum duration before timing out read of the request")
+	fs.DurationVar(&u.Timeouts.Write, "socket-write-timeout", 60*time.Second, "maximum duration before timing out write of the response")
+	fs.DurationVar(&u.Timeouts.Idle, "socket-idle-timeout", 60*time.Second, "maximum amount of time to wait for the next request when keep-alives are enabled")
+}
+
+// Scheme for this listener
+func (u *UnixSocketFlags) Scheme() string { return schemeUnix }
+
+// Listener returns the net.Listener for this flag set, creating it if needed
+func (u *UnixSocketFlags) Listener() (net.Listener, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.listener != nil {
+		return u.listener, nil
+	}
+	if u.Path == "" {
+		return nil, nil
+	}
+	l, err := net.Listen("unix", u.Path)
+	if err != nil {
+		return nil, err
+	}
+	if u.Mode != "" {
+		if mode, err := strconv.ParseUint(u.Mode, 8, 32); err == nil {
+			_ = os.Chmod(u.Path, os.FileMode(mode))
+		}
+	}
+	if u.ListenLimit > 0 {
+		l = netutil.LimitListener(l, u.ListenLimit)
+	}
+	u.listener = l
+	return l, nil
+}
+
+// Serve the configured handler on this listener
+func (u *UnixSocketFlags) Serve(cfg ServerConfig, wg *sync.WaitGroup) (*http.Server, error) {
+	l, err := u.Listener()
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, nil
+	}
+
+	srv := &http.Server{
+		Handler:        cfg.Handler,
+		MaxHeaderBytes: cfg.MaxHeaderSize,
+		ReadTimeout:    cfg.Timeouts.Read,
+		WriteTimeout:   cfg.Timeouts.Write,
+		IdleTimeout:    cfg.Timeouts.Idle,
+	}
+	if cfg.Callbacks != nil {
+		cfg.Callbacks.ConfigureListener(srv, u.Scheme(), u.Path)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			cfg.Logger.Error(err, "unix listener closed")
+		}
+	}()
+	return srv, nil
+}
+
+// TLSFlags holds the configuration for the TLS listener, including the
+// optional mutual TLS client certificate policy.
+type TLSFlags struct {
+	Host           string
+	Port           int
+	Certificate    string
+	CertificateKey string
+	// CACertificate, when set, is used to verify client certificates
+	// presented during the handshake according to ClientAuth.
+	CACertificate string
+	// ClientAuth selects the mutual TLS policy applied to incoming
+	// connections. Defaults to tls.NoClientCert; when a CA is configured
+	// and ClientAuth is left at tls.NoClientCert, tls.VerifyClientCertIfGiven
+	// is applied instead.
+	ClientAuth tls.ClientAuthType
+	// ListenLimit bounds the number of simultaneous in-flight connections
+	// accepted on this listener. Zero or negative disables the bound.
+	ListenLimit int
+	// Timeouts holds the Read/Write/Idle/KeepAlive durations applied to
+	// this listener's http.Server and TCP connections.
+	Timeouts Timeouts
+
+	mu        sync.Mutex
+	listener  net.Listener
+	tlsConfig *tls.Config
+	caPool    *x509.CertPool
+	certStore atomic.Value // holds *tls.Certificate
+}
+
+// RegisterFlags to the specified pflag set
+func (t *TLSFlags) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&t.Host, "tls-host", t.Host, "the IP to listen on for tls, when not specified a selection will be made automatically")
+	fs.IntVar(&t.Port, "tls-port", t.Port, "the port to listen on for secure connections, defaults to a random value")
+	fs.StringVar(&t.Certificate, "tls-certificate", t.Certificate, "the certificate file to use for secure connections")
+	fs.StringVar(&t.CertificateKey, "tls-key", t.CertificateKey, "the private key file to use for secure connections")
+	fs.StringVar(&t.CACertificate, "tls-ca", t.CACertificate, "the certificate authority file to use for verifying mutual TLS client certificates")
+	fs.Var((*clientAuthValue)(&t.ClientAuth), "tls-client-auth", "the mutual TLS client certificate policy, one of NoClientCert, RequestClientCert, RequireAnyClientCert, VerifyClientCertIfGiven, RequireAndVerifyClientCert")
+	fs.IntVar(&t.ListenLimit, "tls-listen-limit", t.ListenLimit, "limit the number of outstanding requests")
+	fs.DurationVar(&t.Timeouts.Read, "tls-read-timeout", 30*time.Second, "maximum duration before timing out read of the request")
+	fs.DurationVar(&t.Timeouts.Write, "tls-write-timeout", 60*time.Second, "maximum duration before timing out write of the response")
+	fs.DurationVar(&t.Timeouts.Idle, "tls-idle-timeout", 60*time.Second, "maximum amount of time to wait for the next request when keep-alives are enabled")
+	fs.DurationVar(&t.Timeouts.KeepAlive, "tls-keep-alive", 3*time.Minute, "TCP keep-alive period for accepted connections, set to 0 to disable")
+}
+
+// Scheme for this listener
+func (t *TLSFlags) Scheme() string { return schemeHTTPS }
+
+func (t *TLSFlags) buildTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.Certificate, t.CertificateKey)
+	if err != nil {
+		return nil, fmt.Errorf("loading tls certificate: %w", err)
+	}
+	t.certStore.Store(&cert)
+
+	cfg := &tls.Config{
+		GetCertificate: t.getCertificate,
+		ClientAuth:     t.ClientAuth,
+	}
+
+	switch {
+	case t.caPool != nil:
+		cfg.ClientCAs = t.caPool
+	case t.CACertificate != "":
+		pem, err := os.ReadFile(t.CACertificate)
+		if err != nil {
+			return nil, fmt.Errorf("reading tls ca certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CACertificate)
+		}
+		cfg.ClientCAs = pool
+	case cfg.ClientAuth == tls.VerifyClientCertIfGiven || cfg.ClientAuth == tls.RequireAndVerifyClientCert:
+		return nil, fmt.Errorf("tls client auth policy %s requires a ca certificate", tls.ClientAuthType(cfg.ClientAuth))
+	}
+	if cfg.ClientCAs != nil && cfg.ClientAuth == tls.NoClientCert {
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return cfg, nil
+}
+
+func (t *TLSFlags) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := t.certStore.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, errors.New("tls: no certificate configured")
+	}
+	return cert, nil
+}
+
+// Reload re-reads the certificate and key from disk and, on success,
+// atomically swaps the certificate served by subsequent TLS handshakes.
+// Connections already established keep using the certificate they were
+// handed; only new handshakes observe the change. On failure the previous
+// certificate keeps being served and the error is returned.
+func (t *TLSFlags) Reload() error {
+	cert, err := tls.LoadX509KeyPair(t.Certificate, t.CertificateKey)
+	if err != nil {
+		return fmt.Errorf("reloading tls certificate: %w", err)
+	}
+	t.certStore.Store(&cert)
+	return nil
+}
+
+func (t *TLSFlags) ensureTLSConfig() (*tls.Config, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tlsConfig != nil {
+		return t.tlsConfig, nil
+	}
+	cfg, err := t.buildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	t.tlsConfig = cfg
+	return cfg, nil
+}
+
+// Listener returns the net.Listener for this flag set, creating it if
+// needed. The returned listener already speaks TLS.
+func (t *TLSFlags) Listener() (net.Listener, error) {
+	t.mu.Lock()
+	if t.listener != nil {
+		defer t.mu.Unlock()
+		return t.listener, nil
+	}
+	t.mu.Unlock()
+
+	if t.Certificate == "" || t.CertificateKey == "" {
+		return nil, nil
+	}
+
+	cfg, err := t.ensureTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	lc := net.ListenConfig{KeepAlive: keepAlivePeriod(t.Timeouts.KeepAlive)}
+	l, err := lc.Listen(context.Background(), "tcp", net.JoinHostPort(t.Host, strconv.Itoa(t.Port)))
+	if err != nil {
+		return nil, err
+	}
+	if t.ListenLimit > 0 {
+		l = netutil.LimitListener(l, t.ListenLimit)
+	}
+
+	t.mu.Lock()
+	t.listener = tls.NewListener(l, cfg)
+	t.mu.Unlock()
+	return t.listener, nil
+}
+
+// Serve the configured handler on this listener
+func (t *TLSFlags) Serve(cfg ServerConfig, wg *sync.WaitGroup) (*http.Server, error) {
+	if t.Certificate == "" || t.CertificateKey == "" {
+		return nil, nil
+	}
+
+	tlsConfig, err := t.ensureTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Callbacks != nil {
+		cfg.Callbacks.ConfigureTLS(tlsConfig)
+	}
+
+	l, err := t.Listener()
+	if err != nil {
+		return nil, err
+	}
+
+	handler := cfg.Handler
+	if tlsConfig.ClientAuth != tls.NoClientCert {
+		handler = withClientCertificate(handler)
+	}
+
+	srv := &http.Server{
+		Handler:        handler,
+		MaxHeaderBytes: cfg.MaxHeaderSize,
+		TLSConfig:      tlsConfig,
+		ReadTimeout:    cfg.Timeouts.Read,
+		WriteTimeout:   cfg.Timeouts.Write,
+		IdleTimeout:    cfg.Timeouts.Idle,
+	}
+	if cfg.Callbacks != nil {
+		cfg.Callbacks.ConfigureListener(srv, t.Scheme(), l.Addr().String())
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			cfg.Logger.Error(err, "https listener closed")
+		}
+	}()
+	return srv, nil
+}
@@ -0,0 +1,357 @@
+package httpd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeTempPEM(t *testing.T, dir, name string, blocks [][]byte, pemType string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", name, err)
+	}
+	defer f.Close()
+	for _, b := range blocks {
+		if err := pem.Encode(f, &pem.Block{Type: pemType, Bytes: b}); err != nil {
+			t.Fatalf("encode %s: %v", name, err)
+		}
+	}
+	return path
+}
+
+func selfSignedCert(t *testing.T, cn string) (certDER []byte, keyDER []byte, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:         true,
+		// x509.CreateCertificate drops the BasicConstraints extension
+		// entirely unless this is set, which left IsCA false once the
+		// certificate was parsed back in and broke chain validation.
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return der, keyBytes, parsed
+}
+
+func TestTLSFlagsBuildTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certDER, keyDER, _ := selfSignedCert(t, "server")
+	caDER, _, _ := selfSignedCert(t, "ca")
+
+	certPath := writeTempPEM(t, dir, "server.crt", [][]byte{certDER}, "CERTIFICATE")
+	keyPath := writeTempPEM(t, dir, "server.key", [][]byte{keyDER}, "EC PRIVATE KEY")
+	caPath := writeTempPEM(t, dir, "ca.crt", [][]byte{caDER}, "CERTIFICATE")
+
+	t.Run("happy path", func(t *testing.T) {
+		f := &TLSFlags{Certificate: certPath, CertificateKey: keyPath, CACertificate: caPath, ClientAuth: tls.RequireAndVerifyClientCert}
+		cfg, err := f.buildTLSConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.ClientCAs == nil {
+			t.Fatal("expected client CAs to be set")
+		}
+		if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+			t.Fatalf("expected RequireAndVerifyClientCert, got %s", cfg.ClientAuth)
+		}
+	})
+
+	t.Run("malformed ca pem", func(t *testing.T) {
+		badCA := filepath.Join(dir, "bad-ca.crt")
+		if err := os.WriteFile(badCA, []byte("not a pem file"), 0o600); err != nil {
+			t.Fatalf("write bad ca: %v", err)
+		}
+		f := &TLSFlags{Certificate: certPath, CertificateKey: keyPath, CACertificate: badCA}
+		if _, err := f.buildTLSConfig(); err == nil {
+			t.Fatal("expected error for malformed CA PEM")
+		}
+	})
+
+	t.Run("missing ca with require and verify", func(t *testing.T) {
+		f := &TLSFlags{Certificate: certPath, CertificateKey: keyPath, ClientAuth: tls.RequireAndVerifyClientCert}
+		if _, err := f.buildTLSConfig(); err == nil {
+			t.Fatal("expected error when CA is missing but client auth requires verification")
+		}
+	})
+}
+
+func TestTLSFlagsListenerFailsListenWithoutCA(t *testing.T) {
+	dir := t.TempDir()
+	certDER, keyDER, _ := selfSignedCert(t, "server")
+	certPath := writeTempPEM(t, dir, "server.crt", [][]byte{certDER}, "CERTIFICATE")
+	keyPath := writeTempPEM(t, dir, "server.key", [][]byte{keyDER}, "EC PRIVATE KEY")
+
+	f := &TLSFlags{
+		Host:           "127.0.0.1",
+		Port:           0,
+		Certificate:    certPath,
+		CertificateKey: keyPath,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+	}
+	if _, err := f.Listener(); err == nil {
+		t.Fatal("expected Listener to fail when CA is missing but required")
+	}
+}
+
+func TestTLSListenerMutualTLSHandshake(t *testing.T) {
+	dir := t.TempDir()
+	serverCertDER, serverKeyDER, _ := selfSignedCert(t, "server")
+	caCertDER, caKeyDER, caCert := selfSignedCert(t, "ca")
+	trustedCertDER, trustedKeyDER := signedClientCert(t, caCert, caKeyDER)
+	untrustedCertDER, untrustedKeyDER, _ := selfSignedCert(t, "untrusted-client")
+
+	certPath := writeTempPEM(t, dir, "server.crt", [][]byte{serverCertDER}, "CERTIFICATE")
+	keyPath := writeTempPEM(t, dir, "server.key", [][]byte{serverKeyDER}, "EC PRIVATE KEY")
+	caPath := writeTempPEM(t, dir, "ca.crt", [][]byte{caCertDER}, "CERTIFICATE")
+
+	f := &TLSFlags{
+		Host:           "127.0.0.1",
+		Port:           0,
+		Certificate:    certPath,
+		CertificateKey: keyPath,
+		CACertificate:  caPath,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+	}
+	l, err := f.Listener()
+	if err != nil {
+		t.Fatalf("unexpected error creating listener: %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				tlsConn := conn.(*tls.Conn)
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				buf := make([]byte, 4)
+				if _, err := tlsConn.Read(buf); err != nil {
+					return
+				}
+				_, _ = tlsConn.Write([]byte("pong"))
+			}()
+		}
+	}()
+
+	serverCACertPool := x509.NewCertPool()
+	serverCACertPool.AddCert(mustParseCert(t, serverCertDER))
+
+	dial := func(certDER, keyDER []byte) error {
+		cert := tlsCertificateFromDER(t, certDER, keyDER)
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      serverCACertPool,
+			ServerName:   "localhost",
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		// With TLS 1.3, a failed mutual auth handshake can complete
+		// locally on the client side before the server's rejection
+		// alert arrives; only a subsequent read/write surfaces it.
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			return err
+		}
+		_, err = conn.Read(make([]byte, 1))
+		return err
+	}
+
+	if err := dial(trustedCertDER, trustedKeyDER); err != nil {
+		t.Fatalf("expected trusted client cert to be accepted: %v", err)
+	}
+	if err := dial(untrustedCertDER, untrustedKeyDER); err == nil {
+		t.Fatal("expected untrusted client cert to be rejected")
+	}
+}
+
+func TestTLSFlagsServeExposesClientCertificateToHandlers(t *testing.T) {
+	dir := t.TempDir()
+	serverCertDER, serverKeyDER, _ := selfSignedCert(t, "server")
+	caCertDER, caKeyDER, caCert := selfSignedCert(t, "ca")
+	trustedCertDER, trustedKeyDER := signedClientCert(t, caCert, caKeyDER)
+
+	certPath := writeTempPEM(t, dir, "server.crt", [][]byte{serverCertDER}, "CERTIFICATE")
+	keyPath := writeTempPEM(t, dir, "server.key", [][]byte{serverKeyDER}, "EC PRIVATE KEY")
+	caPath := writeTempPEM(t, dir, "ca.crt", [][]byte{caCertDER}, "CERTIFICATE")
+
+	var gotSubject string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cert, ok := ClientCertificate(r)
+		if !ok {
+			t.Error("expected a client certificate on the request context")
+			return
+		}
+		gotSubject = cert.Subject.CommonName
+	})
+
+	f := &TLSFlags{
+		Host:           "127.0.0.1",
+		Port:           0,
+		Certificate:    certPath,
+		CertificateKey: keyPath,
+		CACertificate:  caPath,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+	}
+
+	var wg sync.WaitGroup
+	srv, err := f.Serve(ServerConfig{Handler: handler, Logger: &stdLogger{}}, &wg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer srv.Close()
+
+	l, err := f.Listener()
+	if err != nil {
+		t.Fatalf("unexpected error fetching listener: %v", err)
+	}
+
+	serverCACertPool := x509.NewCertPool()
+	serverCACertPool.AddCert(mustParseCert(t, serverCertDER))
+	clientCert := tlsCertificateFromDER(t, trustedCertDER, trustedKeyDER)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{clientCert},
+				RootCAs:      serverCACertPool,
+				ServerName:   "localhost",
+			},
+		},
+	}
+
+	resp, err := client.Get("https://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if gotSubject != "trusted-client" {
+		t.Fatalf("expected handler to observe client certificate subject %q, got %q", "trusted-client", gotSubject)
+	}
+}
+
+func signedClientCert(t *testing.T, ca *x509.Certificate, caKeyDER []byte) (certDER, keyDER []byte) {
+	t.Helper()
+	caKey, err := x509.ParseECPrivateKey(caKeyDER)
+	if err != nil {
+		t.Fatalf("parse ca key: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "trusted-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create client certificate: %v", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal client key: %v", err)
+	}
+	return der, keyBytes
+}
+
+func mustParseCert(t *testing.T, der []byte) *x509.Certificate {
+	t.Helper()
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return cert
+}
+
+func tlsCertificateFromDER(t *testing.T, certDER, keyDER []byte) tls.Certificate {
+	t.Helper()
+	key, err := x509.ParseECPrivateKey(keyDER)
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}
+}
+
+func TestListenLimitWrapsListener(t *testing.T) {
+	h := &HTTPFlags{Host: "127.0.0.1", Port: 0, ListenLimit: 1}
+	l, err := h.Listener()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+	if fmt.Sprintf("%T", l) == "*net.TCPListener" {
+		t.Fatal("expected the listener to be wrapped by netutil.LimitListener")
+	}
+
+	u := &HTTPFlags{Host: "127.0.0.1", Port: 0}
+	l2, err := u.Listener()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l2.Close()
+	if fmt.Sprintf("%T", l2) != "*net.TCPListener" {
+		t.Fatal("expected an unwrapped listener when ListenLimit is not set")
+	}
+}
+
+func TestClientAuthValue(t *testing.T) {
+	var v clientAuthValue
+	if err := v.Set("RequireAndVerifyClientCert"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tls.ClientAuthType(v) != tls.RequireAndVerifyClientCert {
+		t.Fatalf("expected RequireAndVerifyClientCert, got %v", v)
+	}
+	if err := v.Set("NotARealPolicy"); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DialCheck returns a CheckFunc that fails unless a TCP connection to addr
+// can be established within timeout.
+func DialCheck(addr string, timeout time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		d := net.Dialer{Timeout: timeout}
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("dialing %s: %w", addr, err)
+		}
+		return conn.Close()
+	}
+}
+
+// DNSResolveCheck returns a CheckFunc that fails unless host resolves to at
+// least one address within timeout.
+func DNSResolveCheck(host string, timeout time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", host, err)
+		}
+		if len(addrs) == 0 {
+			return fmt.Errorf("resolving %s: no addresses found", host)
+		}
+		return nil
+	}
+}
+
+// DiskSpaceCheck returns a CheckFunc that fails once the free space on the
+// filesystem backing path drops below minPercentFree.
+func DiskSpaceCheck(path string, minPercentFree uint8) CheckFunc {
+	return func(ctx context.Context) error {
+		var stat unix.Statfs_t
+		if err := unix.Statfs(path, &stat); err != nil {
+			return fmt.Errorf("checking disk space for %s: %w", path, err)
+		}
+		if stat.Blocks == 0 {
+			return nil
+		}
+		freePercent := float64(stat.Bavail) / float64(stat.Blocks) * 100
+		if freePercent < float64(minPercentFree) {
+			return fmt.Errorf("%s: %.2f%% free, want at least %d%%", path, freePercent, minPercentFree)
+		}
+		return nil
+	}
+}
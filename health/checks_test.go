@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDialCheck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	if err := DialCheck(l.Addr().String(), time.Second)(context.Background()); err != nil {
+		t.Fatalf("expected dial check to succeed: %v", err)
+	}
+
+	if err := DialCheck("127.0.0.1:1", 100*time.Millisecond)(context.Background()); err == nil {
+		t.Fatal("expected dial check against a closed port to fail")
+	}
+}
+
+func TestDNSResolveCheck(t *testing.T) {
+	if err := DNSResolveCheck("localhost", time.Second)(context.Background()); err != nil {
+		t.Fatalf("expected localhost to resolve: %v", err)
+	}
+
+	if err := DNSResolveCheck("this-host-should-not-resolve.invalid", time.Second)(context.Background()); err == nil {
+		t.Fatal("expected an invalid host to fail to resolve")
+	}
+}
+
+func TestDiskSpaceCheck(t *testing.T) {
+	if err := DiskSpaceCheck(t.TempDir(), 0)(context.Background()); err != nil {
+		t.Fatalf("expected a 0%% threshold to always pass: %v", err)
+	}
+
+	if err := DiskSpaceCheck(t.TempDir(), 100)(context.Background()); err == nil {
+		t.Fatal("expected a 100% free threshold to fail on any real filesystem")
+	}
+}
@@ -0,0 +1,143 @@
+// Package health provides a small readiness/liveness check subsystem meant
+// to be mounted on a server's admin listener.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckFunc reports the health of a single dependency or subsystem. It
+// should return promptly and respect ctx's deadline.
+type CheckFunc func(ctx context.Context) error
+
+// Registry collects liveness and readiness checks and serves them over
+// /livez, /readyz and /healthz.
+type Registry struct {
+	mu        sync.RWMutex
+	liveness  map[string]CheckFunc
+	readiness map[string]CheckFunc
+
+	shuttingDown int32
+}
+
+// NewRegistry returns an empty Registry ready to have checks added to it.
+func NewRegistry() *Registry {
+	return &Registry{
+		liveness:  map[string]CheckFunc{},
+		readiness: map[string]CheckFunc{},
+	}
+}
+
+// AddLivenessCheck registers check under name. It is run for both /livez
+// and /readyz.
+func (r *Registry) AddLivenessCheck(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness[name] = check
+}
+
+// AddReadinessCheck registers check under name. It is run only for
+// /readyz and /healthz.
+func (r *Registry) AddReadinessCheck(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness[name] = check
+}
+
+// Shutdown marks the registry as not ready. Once called, /readyz and
+// /healthz immediately report failure regardless of the registered checks,
+// so load balancers can stop routing before the server starts draining.
+func (r *Registry) Shutdown() {
+	atomic.StoreInt32(&r.shuttingDown, 1)
+}
+
+type checkResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+type report struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks,omitempty"`
+}
+
+func (r *Registry) runChecks(ctx context.Context, checks map[string]CheckFunc) (bool, []checkResult) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ok := true
+	results := make([]checkResult, 0, len(checks))
+	for name, check := range checks {
+		start := time.Now()
+		err := check(ctx)
+		cr := checkResult{Name: name, Status: "ok", Latency: time.Since(start).String()}
+		if err != nil {
+			ok = false
+			cr.Status = "fail"
+			cr.Error = err.Error()
+		}
+		results = append(results, cr)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return ok, results
+}
+
+func (r *Registry) handle(includeReadiness bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ok, results := r.runChecks(req.Context(), r.liveness)
+
+		if includeReadiness {
+			if atomic.LoadInt32(&r.shuttingDown) == 1 {
+				ok = false
+				results = append(results, checkResult{Name: "shutdown", Status: "fail", Error: "server is shutting down"})
+			}
+			readyOK, readyResults := r.runChecks(req.Context(), r.readiness)
+			ok = ok && readyOK
+			results = append(results, readyResults...)
+			sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+		}
+
+		status := http.StatusOK
+		rep := report{Status: "ok"}
+		if !ok {
+			status = http.StatusServiceUnavailable
+			rep.Status = "fail"
+		}
+		if req.URL.Query().Get("verbose") == "1" {
+			rep.Checks = results
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(rep)
+	}
+}
+
+// LivezHandler runs only the registered liveness checks.
+func (r *Registry) LivezHandler() http.Handler { return r.handle(false) }
+
+// ReadyzHandler runs both liveness and readiness checks, and immediately
+// fails once Shutdown has been called.
+func (r *Registry) ReadyzHandler() http.Handler { return r.handle(true) }
+
+// HealthzHandler is an alias for ReadyzHandler kept for operators who probe
+// the conventional /healthz path.
+func (r *Registry) HealthzHandler() http.Handler { return r.handle(true) }
+
+// Handler mounts LivezHandler, ReadyzHandler and HealthzHandler under
+// /livez, /readyz and /healthz respectively.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/livez", r.LivezHandler())
+	mux.Handle("/readyz", r.ReadyzHandler())
+	mux.Handle("/healthz", r.HealthzHandler())
+	return mux
+}
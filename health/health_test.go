@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistryReadyzFailsWhenReadinessCheckFails(t *testing.T) {
+	r := NewRegistry()
+	r.AddLivenessCheck("always-ok", func(context.Context) error { return nil })
+	r.AddReadinessCheck("broken-dependency", func(context.Context) error { return errors.New("dependency down") })
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz?verbose=1", nil)
+	w := httptest.NewRecorder()
+	r.ReadyzHandler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+
+	var rep report
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if rep.Status != "fail" {
+		t.Fatalf("expected status fail, got %s", rep.Status)
+	}
+	if len(rep.Checks) != 2 {
+		t.Fatalf("expected 2 checks in the verbose report, got %d", len(rep.Checks))
+	}
+}
+
+func TestRegistryLivezIgnoresReadinessChecks(t *testing.T) {
+	r := NewRegistry()
+	r.AddLivenessCheck("always-ok", func(context.Context) error { return nil })
+	r.AddReadinessCheck("broken-dependency", func(context.Context) error { return errors.New("dependency down") })
+
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	w := httptest.NewRecorder()
+	r.LivezHandler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected /livez to ignore readiness checks, got %d", w.Code)
+	}
+}
+
+func TestRegistryReadyzFailsImmediatelyAfterShutdown(t *testing.T) {
+	r := NewRegistry()
+	r.AddLivenessCheck("always-ok", func(context.Context) error { return nil })
+	r.AddReadinessCheck("always-ok", func(context.Context) error { return nil })
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	r.ReadyzHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 before shutdown, got %d", w.Code)
+	}
+
+	r.Shutdown()
+
+	w = httptest.NewRecorder()
+	r.ReadyzHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after shutdown even though all checks pass, got %d", w.Code)
+	}
+}
+
+func TestRegistryHandlerMountsAllThreePaths(t *testing.T) {
+	r := NewRegistry()
+	handler := r.Handler()
+
+	for _, path := range []string{"/livez", "/readyz", "/healthz"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected %s to return 200 with no checks registered, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package httpd
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casualjim/go-httpd/health"
+)
+
+func TestWithHealthReplacesAdminHandler(t *testing.T) {
+	registry := health.NewRegistry()
+	s := New(WithHealth(registry)).(*defaultServer)
+
+	if s.health != registry {
+		t.Fatal("expected WithHealth to replace the server's health registry")
+	}
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	s.adminHandler.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected /readyz to succeed with no checks registered, got %d", w.Code)
+	}
+}
+
+func TestShutdownMarksHealthRegistryNotReady(t *testing.T) {
+	registry := health.NewRegistry()
+	s := New(WithHealth(registry)).(*defaultServer)
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	s.adminHandler.ServeHTTP(w, req)
+	if w.Code != 503 {
+		t.Fatalf("expected /readyz to fail once the server has started shutting down, got %d", w.Code)
+	}
+}
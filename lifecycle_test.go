@@ -0,0 +1,73 @@
+package httpd
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type recordingLifecycleHook struct {
+	beforeStart    error
+	beforeShutdown time.Duration
+
+	calls []string
+}
+
+func (r *recordingLifecycleHook) BeforeStart() error {
+	r.calls = append(r.calls, "BeforeStart")
+	return r.beforeStart
+}
+
+func (r *recordingLifecycleHook) AfterStart() {
+	r.calls = append(r.calls, "AfterStart")
+}
+
+func (r *recordingLifecycleHook) BeforeShutdown() time.Duration {
+	r.calls = append(r.calls, "BeforeShutdown")
+	return r.beforeShutdown
+}
+
+func (r *recordingLifecycleHook) AfterShutdown(error) {
+	r.calls = append(r.calls, "AfterShutdown")
+}
+
+func TestCompositeLifecycleHookFansOutToAllHooks(t *testing.T) {
+	a := &recordingLifecycleHook{beforeShutdown: 5 * time.Second}
+	b := &recordingLifecycleHook{beforeShutdown: 20 * time.Second}
+	c := &compositeLifecycleHook{hooks: []LifecycleHook{a, b}}
+
+	if err := c.BeforeStart(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.AfterStart()
+	if got := c.BeforeShutdown(); got != 20*time.Second {
+		t.Fatalf("expected the largest requested shutdown extension to win, got %s", got)
+	}
+	c.AfterShutdown(nil)
+
+	for _, h := range []*recordingLifecycleHook{a, b} {
+		want := []string{"BeforeStart", "AfterStart", "BeforeShutdown", "AfterShutdown"}
+		if len(h.calls) != len(want) {
+			t.Fatalf("expected calls %v, got %v", want, h.calls)
+		}
+		for i := range want {
+			if h.calls[i] != want[i] {
+				t.Fatalf("expected calls %v, got %v", want, h.calls)
+			}
+		}
+	}
+}
+
+func TestCompositeLifecycleHookStopsOnFirstBeforeStartError(t *testing.T) {
+	boom := errors.New("boom")
+	a := &recordingLifecycleHook{beforeStart: boom}
+	b := &recordingLifecycleHook{}
+	c := &compositeLifecycleHook{hooks: []LifecycleHook{a, b}}
+
+	if err := c.BeforeStart(); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if len(b.calls) != 0 {
+		t.Fatal("expected the second hook to be skipped after the first returns an error")
+	}
+}
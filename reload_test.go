@@ -0,0 +1,95 @@
+package httpd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+)
+
+func pemBytes(der []byte, pemType string) []byte {
+	var buf bytes.Buffer
+	_ = pem.Encode(&buf, &pem.Block{Type: pemType, Bytes: der})
+	return buf.Bytes()
+}
+
+func TestTLSFlagsReloadSwapsCertificateWithoutDroppingConnections(t *testing.T) {
+	dir := t.TempDir()
+	firstCertDER, firstKeyDER, firstCert := selfSignedCert(t, "first")
+	secondCertDER, secondKeyDER, secondCert := selfSignedCert(t, "second")
+
+	certPath := writeTempPEM(t, dir, "server.crt", [][]byte{firstCertDER}, "CERTIFICATE")
+	keyPath := writeTempPEM(t, dir, "server.key", [][]byte{firstKeyDER}, "EC PRIVATE KEY")
+
+	f := &TLSFlags{Host: "127.0.0.1", Port: 0, Certificate: certPath, CertificateKey: keyPath}
+	l, err := f.Listener()
+	if err != nil {
+		t.Fatalf("unexpected error creating listener: %v", err)
+	}
+	defer l.Close()
+
+	var wg sync.WaitGroup
+	srv, err := f.Serve(ServerConfig{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), Logger: &stdLogger{}}, &wg)
+	if err != nil {
+		t.Fatalf("unexpected error serving: %v", err)
+	}
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(firstCert)
+	pool.AddCert(secondCert)
+
+	dialAndGetLeaf := func() *x509.Certificate {
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost"})
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		leaf := conn.ConnectionState().PeerCertificates[0]
+		return leaf
+	}
+
+	existing, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("dial existing connection: %v", err)
+	}
+	defer existing.Close()
+
+	if leaf := dialAndGetLeaf(); leaf.Subject.CommonName != "first" {
+		t.Fatalf("expected the first certificate before reload, got %s", leaf.Subject.CommonName)
+	}
+
+	if err := os.WriteFile(certPath, pemBytes(secondCertDER, "CERTIFICATE"), 0o600); err != nil {
+		t.Fatalf("write second cert: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pemBytes(secondKeyDER, "EC PRIVATE KEY"), 0o600); err != nil {
+		t.Fatalf("write second key: %v", err)
+	}
+
+	if err := f.Reload(); err != nil {
+		t.Fatalf("unexpected error reloading: %v", err)
+	}
+
+	if leaf := dialAndGetLeaf(); leaf.Subject.CommonName != "second" {
+		t.Fatalf("expected the second certificate after reload, got %s", leaf.Subject.CommonName)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://"+l.Addr().String()+"/", nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	if err := req.Write(existing); err != nil {
+		t.Fatalf("expected the pre-reload connection to still accept writes: %v", err)
+	}
+	buf := make([]byte, 1)
+	if _, err := existing.Read(buf); err != nil && err != io.EOF {
+		t.Fatalf("expected the pre-reload connection to still be usable: %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package httpd
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"github.com/go-logr/logr"
 	"log"
@@ -18,6 +20,7 @@ import (
 	"time"
 
 	"github.com/a-h/hsts"
+	"github.com/casualjim/go-httpd/health"
 	flag "github.com/spf13/pflag"
 )
 
@@ -41,6 +44,71 @@ type Hook interface {
 	ConfigureListener(*http.Server, string, string)
 }
 
+// LifecycleHook runs code at well-defined points in the server's lifecycle,
+// complementing the per-listener Hook interface. It's a separate interface
+// so existing Hook implementers don't need to change.
+type LifecycleHook interface {
+	// BeforeStart runs once, after Serve is called but before any
+	// net.Listen call, so implementations can validate configuration or
+	// open external resources. Returning an error aborts Serve.
+	BeforeStart() error
+	// AfterStart runs once every listener is accepting connections, e.g.
+	// to publish readiness or register with service discovery.
+	AfterStart()
+	// BeforeShutdown runs as soon as Shutdown has been requested, before
+	// the graceful shutdown context is created, so implementations can
+	// fail readiness probes and let load balancers drain in time. A
+	// returned duration greater than the default shutdown timeout
+	// extends the drain window.
+	BeforeShutdown() time.Duration
+	// AfterShutdown runs once every listener has shut down, after
+	// onShutdown. err is the aggregated shutdown error, if any; when it is
+	// non-nil, onShutdown is skipped and AfterShutdown still runs.
+	AfterShutdown(err error)
+}
+
+type noopLifecycleHook struct{}
+
+func (noopLifecycleHook) BeforeStart() error            { return nil }
+func (noopLifecycleHook) AfterStart()                   {}
+func (noopLifecycleHook) BeforeShutdown() time.Duration { return 0 }
+func (noopLifecycleHook) AfterShutdown(error)           {}
+
+type compositeLifecycleHook struct {
+	hooks []LifecycleHook
+}
+
+func (c *compositeLifecycleHook) BeforeStart() error {
+	for _, h := range c.hooks {
+		if err := h.BeforeStart(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compositeLifecycleHook) AfterStart() {
+	for _, h := range c.hooks {
+		h.AfterStart()
+	}
+}
+
+func (c *compositeLifecycleHook) BeforeShutdown() time.Duration {
+	var extra time.Duration
+	for _, h := range c.hooks {
+		if d := h.BeforeShutdown(); d > extra {
+			extra = d
+		}
+	}
+	return extra
+}
+
+func (c *compositeLifecycleHook) AfterShutdown(err error) {
+	for _, h := range c.hooks {
+		h.AfterShutdown(err)
+	}
+}
+
 var (
 	enabledListeners []string
 	cleanupTimout    time.Duration
@@ -50,6 +118,11 @@ var (
 	DefaultHTTPFlags HTTPFlags
 	DefaultTLSFlags  TLSFlags
 
+	// DefaultHealthRegistry backs DefaultAdminHandler's /livez, /readyz
+	// and /healthz endpoints. Register checks on it, or override it
+	// wholesale with WithHealth, before calling New.
+	DefaultHealthRegistry *health.Registry
+
 	DefaultAdminHandler http.Handler
 )
 
@@ -62,10 +135,8 @@ func init() {
 	DefaultTLSFlags.Certificate = stringEnvOverride(DefaultTLSFlags.Certificate, "", "TLS_CERTIFICATE")
 	DefaultTLSFlags.CertificateKey = stringEnvOverride(DefaultTLSFlags.CertificateKey, "", "TLS_PRIVATE_KEY")
 	DefaultTLSFlags.CACertificate = stringEnvOverride(DefaultTLSFlags.CACertificate, "", "TLS_CA_CERTIFICATE")
-	DefaultAdminHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	DefaultHealthRegistry = health.NewRegistry()
+	DefaultAdminHandler = DefaultHealthRegistry.Handler()
 }
 
 // RegisterFlags to the specified pflag set
@@ -130,6 +201,17 @@ func Hooks(hook Hook, extra ...Hook) Option {
 	}
 }
 
+// WithLifecycle registers one or more LifecycleHooks for the server to call
+// at BeforeStart, AfterStart, BeforeShutdown and AfterShutdown.
+func WithLifecycle(hook LifecycleHook, extra ...LifecycleHook) Option {
+	h := &compositeLifecycleHook{
+		hooks: append([]LifecycleHook{hook}, extra...),
+	}
+	return func(s *defaultServer) {
+		s.lifecycle = h
+	}
+}
+
 type compositeHook struct {
 	hooks []Hook
 }
@@ -212,6 +294,17 @@ func HandlesAdminWith(handler http.Handler) Option {
 	}
 }
 
+// WithHealth replaces the registry backing the default admin handler's
+// /livez, /readyz and /healthz endpoints. Options are applied in order, so a
+// later HandlesAdminWith or WithAdmin replaces the admin handler this sets;
+// the registry is still marked not ready when Shutdown is called.
+func WithHealth(registry *health.Registry) Option {
+	return func(s *defaultServer) {
+		s.health = registry
+		s.adminHandler = registry.Handler()
+	}
+}
+
 // WithAdminListeners configures the handler and the listeners for the admin endpoint (like /healthz, /readyz, /metrics)
 func WithAdmin(handler http.Handler, listener ServerListener, extra ...ServerListener) Option {
 	all := append([]ServerListener{listener}, extra...)
@@ -221,6 +314,68 @@ func WithAdmin(handler http.Handler, listener ServerListener, extra ...ServerLis
 	}
 }
 
+// WithTLSClientAuth enables mutual TLS on the server's TLS listener(s),
+// verifying client certificates against caPEM according to policy. Use this
+// when configuring the server from code instead of pflag.
+func WithTLSClientAuth(policy tls.ClientAuthType, caPEM []byte) Option {
+	return func(s *defaultServer) {
+		for _, l := range append(append([]ServerListener{}, s.listeners...), s.adminListeners...) {
+			t, ok := l.(*TLSFlags)
+			if !ok {
+				continue
+			}
+			t.ClientAuth = policy
+			if len(caPEM) > 0 {
+				pool := x509.NewCertPool()
+				if pool.AppendCertsFromPEM(caPEM) {
+					t.caPool = pool
+				}
+			}
+		}
+	}
+}
+
+// WithListenLimit bounds the number of simultaneous in-flight connections
+// accepted by the listener(s) registered for scheme, e.g. "http", "https" or
+// "unix". Zero or negative values leave the listener unbounded.
+func WithListenLimit(scheme string, n int) Option {
+	return func(s *defaultServer) {
+		for _, l := range append(append([]ServerListener{}, s.listeners...), s.adminListeners...) {
+			if l.Scheme() != scheme {
+				continue
+			}
+			switch v := l.(type) {
+			case *HTTPFlags:
+				v.ListenLimit = n
+			case *TLSFlags:
+				v.ListenLimit = n
+			case *UnixSocketFlags:
+				v.ListenLimit = n
+			}
+		}
+	}
+}
+
+// WithTimeouts sets the Read/Write/Idle/KeepAlive timeout matrix on the
+// listener(s) registered for scheme, e.g. "http", "https" or "unix".
+func WithTimeouts(scheme string, t Timeouts) Option {
+	return func(s *defaultServer) {
+		for _, l := range append(append([]ServerListener{}, s.listeners...), s.adminListeners...) {
+			if l.Scheme() != scheme {
+				continue
+			}
+			switch v := l.(type) {
+			case *HTTPFlags:
+				v.Timeouts = t
+			case *TLSFlags:
+				v.Timeouts = t
+			case *UnixSocketFlags:
+				v.Timeouts = t
+			}
+		}
+	}
+}
+
 func EnableHSTS(maxAge time.Duration, sendPreload bool) Option {
 	if maxAge == 0 {
 		maxAge = time.Hour * 24 * 126 // 126 days (minimum for inclusion in the Chrome HSTS list)
@@ -243,9 +398,11 @@ func New(opts ...Option) Server {
 	s.shutdown = make(chan struct{})
 	s.interrupt = make(chan os.Signal, 1)
 	s.logger = &stdLogger{}
+	s.lifecycle = noopLifecycleHook{}
 	s.onShutdown = func() {}
 	s.listeners = []ServerListener{&DefaultUDSFlags, &DefaultHTTPFlags, &DefaultTLSFlags}
 	s.adminHandler = DefaultAdminHandler
+	s.health = DefaultHealthRegistry
 
 	for _, apply := range opts {
 		apply(s)
@@ -271,6 +428,22 @@ type ServerConfig struct {
 	Handler        http.Handler
 	Callbacks      Hook
 	CleanupTimeout time.Duration
+	Timeouts       Timeouts
+}
+
+// timeoutsFor returns the Timeouts configured on l, if it is one of the
+// listener types that carries its own scheme-specific timeout flags.
+func timeoutsFor(l ServerListener) Timeouts {
+	switch v := l.(type) {
+	case *HTTPFlags:
+		return v.Timeouts
+	case *TLSFlags:
+		return v.Timeouts
+	case *UnixSocketFlags:
+		return v.Timeouts
+	default:
+		return Timeouts{}
+	}
 }
 
 type ServerListener interface {
@@ -293,9 +466,11 @@ type defaultServer struct {
 	interrupted  bool
 	interrupt    chan os.Signal
 	callbacks    Hook
+	lifecycle    LifecycleHook
 	logger       logr.Logger
 
 	hsts           *hstsConfig
+	health         *health.Registry
 	onShutdown     func()
 	listeners      []ServerListener
 	adminListeners []ServerListener
@@ -317,6 +492,10 @@ func (s *defaultServer) hasScheme(scheme string) bool {
 
 // Serve the api
 func (s *defaultServer) Serve() (err error) {
+	if err := s.lifecycle.BeforeStart(); err != nil {
+		return err
+	}
+
 	if err := s.Listen(); err != nil {
 		return err
 	}
@@ -340,6 +519,7 @@ func (s *defaultServer) Serve() (err error) {
 			MaxHeaderSize:  int(s.MaxHeaderSize),
 			Handler:        s.handler,
 			Logger:         s.logger,
+			Timeouts:       timeoutsFor(server),
 		}
 		if hs, err := server.Serve(sc, &wg); err == nil {
 			servers = append(servers, hs)
@@ -354,6 +534,7 @@ func (s *defaultServer) Serve() (err error) {
 			MaxHeaderSize:  int(s.MaxHeaderSize),
 			Handler:        s.adminHandler,
 			Logger:         s.logger,
+			Timeouts:       timeoutsFor(server),
 		}
 		if hs, err := server.Serve(sc, &wg); err == nil {
 			servers = append(servers, hs)
@@ -362,6 +543,8 @@ func (s *defaultServer) Serve() (err error) {
 		}
 	}
 
+	s.lifecycle.AfterStart()
+
 	wg.Wait()
 	return nil
 }
@@ -383,6 +566,9 @@ func (s *defaultServer) Listen() error {
 // Shutdown server and clean up resources
 func (s *defaultServer) Shutdown() error {
 	if atomic.CompareAndSwapInt32(&s.shuttingDown, 0, 1) {
+		if s.health != nil {
+			s.health.Shutdown()
+		}
 		close(s.shutdown)
 	}
 	return nil
@@ -395,38 +581,42 @@ func (s *defaultServer) handleShutdown(wg *sync.WaitGroup, serversPtr *[]*http.S
 
 	<-s.shutdown
 
+	timeout := 15 * time.Second
+	if extra := s.lifecycle.BeforeShutdown(); extra > timeout {
+		timeout = extra
+	}
+
 	servers := *serversPtr
 
-	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
 	defer cancel()
 
-	shutdownChan := make(chan bool)
+	errChan := make(chan error)
 	for i := range servers {
 		server := servers[i]
 		go func() {
-			var success bool
-			defer func() {
-				shutdownChan <- success
-			}()
-			if err := server.Shutdown(ctx); err != nil {
-				// Error from closing listeners, or context timeout:
-				s.logger.Error(err, "HTTP server Shutdown.")
-			} else {
-				success = true
-			}
+			errChan <- server.Shutdown(ctx)
 		}()
 	}
 
 	// Wait until all listeners have successfully shut down before calling ServerShutdown
-	success := true
+	var errs []error
 	for range servers {
-		success = success && <-shutdownChan
-	}
-	if success {
-		if s.onShutdown != nil {
-			s.onShutdown()
+		if err := <-errChan; err != nil {
+			// Error from closing listeners, or context timeout: this is
+			// aggregated and handed to AfterShutdown below, so it must not
+			// be logged through Error, which the default logger treats as
+			// fatal and would prevent AfterShutdown from ever running.
+			s.logger.Info("HTTP server Shutdown: %v", err)
+			errs = append(errs, err)
 		}
 	}
+
+	shutdownErr := errors.Join(errs...)
+	if shutdownErr == nil && s.onShutdown != nil {
+		s.onShutdown()
+	}
+	s.lifecycle.AfterShutdown(shutdownErr)
 }
 
 // GetHandler returns a handler useful for testing
@@ -475,7 +665,17 @@ func (s *defaultServer) TLSListener() (net.Listener, error) {
 
 func handleInterrupt(once *sync.Once, s *defaultServer) {
 	once.Do(func() {
-		for range s.interrupt {
+		for sig := range s.interrupt {
+			if sig == syscall.SIGHUP {
+				if err := s.Reload(); err != nil {
+					// Reload failures are non-fatal by design: the
+					// previous certificate keeps being served. Error is
+					// fatal on the default logger, so this must not use
+					// it or a bad cert on disk would take the server down.
+					s.logger.Info("error reloading tls certificate: %v", err)
+				}
+				continue
+			}
 			if s.interrupted {
 				continue
 			}
@@ -489,7 +689,28 @@ func handleInterrupt(once *sync.Once, s *defaultServer) {
 }
 
 func signalNotify(interrupt chan<- os.Signal) {
-	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+}
+
+// Reload re-reads the TLS certificate and key from disk for every TLS
+// listener and atomically swaps them in, without dropping connections
+// already established. Use this to trigger a reload from orchestrators
+// that can't send SIGHUP (Windows, k8s exec probes).
+func (s *defaultServer) Reload() error {
+	var first error
+	for _, l := range append(append([]ServerListener{}, s.listeners...), s.adminListeners...) {
+		t, ok := l.(*TLSFlags)
+		if !ok {
+			continue
+		}
+		if err := t.Reload(); err != nil {
+			s.logger.Info("failed to reload tls certificate: %v", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
 }
 
 // Server is the interface a server implements
@@ -503,6 +724,7 @@ type Server interface {
 	Listen() error
 	Serve() error
 	Shutdown() error
+	Reload() error
 }
 
 type stdLogger struct {
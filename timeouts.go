@@ -0,0 +1,27 @@
+package httpd
+
+import "time"
+
+// Timeouts bundles the duration knobs applied to a listener's http.Server
+// and its underlying TCP connections. A zero value for any field disables
+// that particular timeout (or, for KeepAlive, disables OS-level TCP
+// keep-alive probing) rather than falling back to a default. The exception
+// is Idle: a zero Idle is handed to http.Server as is, and http.Server then
+// applies the Read timeout between requests; Idle is disabled only when Read
+// is zero as well.
+type Timeouts struct {
+	Read      time.Duration
+	Write     time.Duration
+	Idle      time.Duration
+	KeepAlive time.Duration
+}
+
+// keepAlivePeriod translates a Timeouts.KeepAlive value into the period
+// understood by net.ListenConfig.KeepAlive, where zero means "use the
+// platform default" rather than "disabled".
+func keepAlivePeriod(d time.Duration) time.Duration {
+	if d == 0 {
+		return -1
+	}
+	return d
+}
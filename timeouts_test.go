@@ -0,0 +1,47 @@
+package httpd
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPFlagsServeAppliesTimeouts(t *testing.T) {
+	h := &HTTPFlags{Host: "127.0.0.1", Port: 0}
+	cfg := ServerConfig{
+		Handler: http.NotFoundHandler(),
+		Logger:  &stdLogger{},
+		Timeouts: Timeouts{
+			Read:  5 * time.Second,
+			Write: 10 * time.Second,
+			Idle:  15 * time.Second,
+		},
+	}
+
+	var wg sync.WaitGroup
+	srv, err := h.Serve(cfg, &wg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer srv.Close()
+
+	if srv.ReadTimeout != 5*time.Second {
+		t.Errorf("expected ReadTimeout 5s, got %s", srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != 10*time.Second {
+		t.Errorf("expected WriteTimeout 10s, got %s", srv.WriteTimeout)
+	}
+	if srv.IdleTimeout != 15*time.Second {
+		t.Errorf("expected IdleTimeout 15s, got %s", srv.IdleTimeout)
+	}
+}
+
+func TestKeepAlivePeriod(t *testing.T) {
+	if keepAlivePeriod(0) != -1 {
+		t.Fatal("expected zero KeepAlive to disable the probe")
+	}
+	if keepAlivePeriod(30*time.Second) != 30*time.Second {
+		t.Fatal("expected a non-zero KeepAlive to pass through unchanged")
+	}
+}